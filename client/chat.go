@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/burntcarrot/pairpad/commons"
+)
+
+// sendChatMessage broadcasts text as a ChatMessage and echoes it into the
+// local scrollback immediately, rather than waiting for it to come back
+// over conn. It backs the Ctrl+G chat flow in client/ui.go.
+func sendChatMessage(text string, conn Transport) {
+	if text == "" {
+		return
+	}
+
+	msg := commons.Message{
+		Type:      commons.ChatMessage,
+		Username:  username,
+		Text:      text,
+		Timestamp: time.Now().Unix(),
+	}
+
+	e.AppendChatLine(fmt.Sprintf("%s: %s", username, text))
+
+	if err := conn.Send(msg); err != nil {
+		e.StatusChan <- "failed to send chat message!"
+	}
+}
@@ -1,128 +1,239 @@
 package main
 
 import (
-	"errors"
 	"fmt"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/burntcarrot/pairpad/commons"
 	"github.com/burntcarrot/pairpad/crdt"
-	"github.com/gorilla/websocket"
-	"github.com/nsf/termbox-go"
+	"github.com/burntcarrot/pairpad/editor"
 	"github.com/sirupsen/logrus"
 )
 
-// handleTermboxEvent handles key input by updating the local CRDT document
-// and sending a message over the WebSocket connection.
-func handleTermboxEvent(ev termbox.Event, conn *websocket.Conn) error {
-	// We only want to deal with termbox key events (EventKey).
-	if ev.Type == termbox.EventKey {
-		switch ev.Key {
-
-		// The default keys for exiting an session are Esc and Ctrl+C.
-		case termbox.KeyEsc, termbox.KeyCtrlC:
-			// Return an error with the prefix "pairpad", so that it gets treated as an exit "event".
-			return errors.New("pairpad: exiting")
-
-		// The default key for saving the editor's contents is Ctrl+S.
-		case termbox.KeyCtrlS:
-			// If no file name is specified, set filename to "pairpad-content.txt"
-			if fileName == "" {
-				fileName = "pairpad-content.txt"
-			}
+// activeConn is swapped in by resync once a dropped connection has been
+// reconnected; the next redraw's keybinding handlers pick it up in place
+// of the stale Transport they'd otherwise have closed over.
+var activeConn Transport
+
+// presenceDebounce is how long we wait for cursor movement to settle
+// before broadcasting a PresenceMessage, so a held arrow key doesn't
+// flood peers with one message per repeat.
+const presenceDebounce = 50 * time.Millisecond
+
+// presenceTimer debounces outgoing presence broadcasts; it is only ever
+// touched from the gocui UI goroutine.
+var presenceTimer *time.Timer
+
+// batchWindow is how long a burst of local edits is left to accumulate
+// before flushBatch sends them as a single BatchOperationMessage, so a
+// held Tab or a paste-like burst of keystrokes costs one round trip
+// instead of one per character.
+const batchWindow = 20 * time.Millisecond
+
+// batchMu guards batchTimer and pendingOps below, since flushBatch runs on
+// its own time.AfterFunc goroutine rather than the gocui UI goroutine that
+// appends to pendingOps.
+var batchMu sync.Mutex
+var batchTimer *time.Timer
+var pendingOps []commons.Operation
+
+// saveToFile writes the current document to fileName (defaulting to
+// pairpad-content.txt), reporting the outcome via e.StatusChan. It backs
+// the Ctrl+S keybinding registered in client/ui.go.
+func saveToFile() error {
+	if fileName == "" {
+		fileName = "pairpad-content.txt"
+	}
 
-			// Save the CRDT to a file.
-			err := crdt.Save(fileName, &doc)
-			if err != nil {
-				logrus.Errorf("Failed to save to %s", fileName)
-				e.StatusChan <- fmt.Sprintf("Failed to save to %s", fileName)
-				return err
-			}
+	docMu.Lock()
+	err := crdt.Save(fileName, &doc)
+	docMu.Unlock()
+	if err != nil {
+		logrus.Errorf("Failed to save to %s", fileName)
+		e.StatusChan <- fmt.Sprintf("Failed to save to %s", fileName)
+		return err
+	}
 
-			// Set the status bar.
-			e.StatusChan <- fmt.Sprintf("Saved document to %s", fileName)
-
-		// The default key for loading content from a file is Ctrl+L.
-		case termbox.KeyCtrlL:
-			if fileName != "" {
-				logger.Log(logrus.InfoLevel, "LOADING DOCUMENT")
-				newDoc, err := crdt.Load(fileName)
-				if err != nil {
-					logrus.Errorf("failed to load file %s", fileName)
-					e.StatusChan <- fmt.Sprintf("Failed to load %s", fileName)
-					return err
-				}
-				e.StatusChan <- fmt.Sprintf("Loading %s", fileName)
-				doc = newDoc
-				e.SetX(0)
-				e.SetText(crdt.Content(doc))
-
-				logger.Log(logrus.InfoLevel, "SENDING DOCUMENT")
-				docMsg := commons.Message{Type: commons.DocSyncMessage, Document: doc}
-				_ = conn.WriteJSON(&docMsg)
-			} else {
-				e.StatusChan <- "No file to load!"
-			}
+	e.StatusChan <- fmt.Sprintf("Saved document to %s", fileName)
+	return nil
+}
 
-		// The default keys for moving left inside the text area are the left arrow key, and Ctrl+B (move backward).
-		case termbox.KeyArrowLeft, termbox.KeyCtrlB:
-			e.MoveCursor(-1, 0)
+// loadFromFile replaces the local document with the contents of fileName
+// and broadcasts it to peers. It backs the Ctrl+L keybinding registered
+// in client/ui.go.
+func loadFromFile(conn Transport) error {
+	if fileName == "" {
+		e.StatusChan <- "No file to load!"
+		return nil
+	}
 
-		// The default keys for moving right inside the text area are the right arrow key, and Ctrl+F (move forward).
-		case termbox.KeyArrowRight, termbox.KeyCtrlF:
-			e.MoveCursor(1, 0)
+	logger.Log(logrus.InfoLevel, "LOADING DOCUMENT")
+	newDoc, err := crdt.Load(fileName)
+	if err != nil {
+		logrus.Errorf("failed to load file %s", fileName)
+		e.StatusChan <- fmt.Sprintf("Failed to load %s", fileName)
+		return err
+	}
+	e.StatusChan <- fmt.Sprintf("Loading %s", fileName)
 
-		// The default keys for moving up inside the text area are the up arrow key, and Ctrl+P (move to previous line).
-		case termbox.KeyArrowUp, termbox.KeyCtrlP:
-			e.MoveCursor(0, -1)
+	docMu.Lock()
+	doc = newDoc
+	content := crdt.Content(doc)
+	docMsg := commons.Message{Type: commons.DocSyncMessage, Document: doc}
+	docMu.Unlock()
 
-		// The default keys for moving down inside the text area are the down arrow key, and Ctrl+N (move to next line).
-		case termbox.KeyArrowDown, termbox.KeyCtrlN:
-			e.MoveCursor(0, 1)
+	e.SetX(0)
+	e.SetText(content)
 
-		// Home key, moves cursor to initial position (X=0).
-		case termbox.KeyHome:
-			e.SetX(0)
+	logger.Log(logrus.InfoLevel, "SENDING DOCUMENT")
+	_ = conn.Send(docMsg)
+	return nil
+}
 
-		// End key, moves cursor to final position (X= length of text).
-		case termbox.KeyEnd:
-			e.SetX(len(e.Text))
-
-		// The default keys for deleting a character are Backspace and Delete.
-		case termbox.KeyBackspace, termbox.KeyBackspace2:
-			performOperation(OperationDelete, ev, conn)
-		case termbox.KeyDelete:
-			performOperation(OperationDelete, ev, conn)
-
-		// The Tab key inserts 4 spaces to simulate a "tab".
-		case termbox.KeyTab:
-			for i := 0; i < 4; i++ {
-				ev.Ch = ' '
-				performOperation(OperationInsert, ev, conn)
-			}
+// startOrClearSelection begins tracking a selection anchor on the first
+// Shift+arrow press, or drops it once a plain arrow press moves the
+// cursor without Shift held.
+func startOrClearSelection(shiftHeld bool) {
+	if shiftHeld {
+		if e.SelectionAnchor() == -1 {
+			e.SetSelectionAnchor(e.CursorPos())
+		}
+		return
+	}
+	e.SetSelectionAnchor(-1)
+}
 
-		// The Enter key inserts a newline character to the editor's content.
-		case termbox.KeyEnter:
-			ev.Ch = '\n'
-			performOperation(OperationInsert, ev, conn)
+// schedulePresenceBroadcast debounces outgoing PresenceMessages so that a
+// burst of cursor movement (e.g. a held arrow key) collapses into a single
+// broadcast ~50ms after it settles.
+func schedulePresenceBroadcast(conn Transport) {
+	if presenceTimer != nil {
+		presenceTimer.Stop()
+	}
+	presenceTimer = time.AfterFunc(presenceDebounce, func() {
+		broadcastPresence(conn)
+	})
+}
 
-		// The Space key inserts a space character to the editor's content.
-		case termbox.KeySpace:
-			ev.Ch = ' '
-			performOperation(OperationInsert, ev, conn)
+// broadcastPresence sends the local cursor/selection, anchored to stable
+// CRDT element IDs rather than raw indices, so that remote insertions and
+// deletions don't make the caret appear to jump.
+func broadcastPresence(conn Transport) {
+	if !e.IsConnected {
+		return
+	}
 
-		// Every other key is eligible to be a candidate for insertion.
-		default:
-			if ev.Ch != 0 {
-				performOperation(OperationInsert, ev, conn)
-			}
-		}
+	cursor := e.CursorPos()
+	anchor := e.SelectionAnchor()
+	if anchor == -1 {
+		anchor = cursor
 	}
 
-	e.SendDraw()
-	return nil
+	docMu.Lock()
+	anchorID, _ := crdt.IdentifierAt(doc, anchor)
+	headID, _ := crdt.IdentifierAt(doc, cursor)
+	docMu.Unlock()
+
+	msg := commons.Message{
+		Type: commons.PresenceMessage,
+		Presence: commons.Presence{
+			SiteID:   crdt.SiteID,
+			Username: username,
+			AnchorID: anchorID,
+			HeadID:   headID,
+			Color:    localPresenceColor,
+		},
+	}
+
+	if err := conn.Send(msg); err != nil {
+		e.IsConnected = false
+		e.StatusChan <- "lost connection!"
+	}
+}
+
+// localPresenceColor is the color remote peers use to render this site's
+// caret and selection. applySiteID sets it from the site ID on every
+// SiteIDMessage (at startup and again after a reconnect); until then it
+// falls back to a default of cyan.
+var localPresenceColor = "cyan"
+
+// editOp records enough about a single local insert or delete to invert it
+// for undo, and to invert it back for redo. Remote operations never reach
+// these stacks, so only edits made at this site can be undone here.
+//
+// before and after are the cursor position immediately before and after
+// the edit was applied; undo needs the former (it recreates the pre-edit
+// state) and redo needs the latter (it recreates the post-edit state), so
+// both are kept rather than reusing one for both directions.
+type editOp struct {
+	id       crdt.Identifier
+	before   int
+	after    int
+	undoType string // wire Operation.Type to send when undoing this edit
+	redoType string // wire Operation.Type to send when redoing it
+}
+
+var undoStack []editOp
+var redoStack []editOp
+
+// pushUndo records a local edit and, per the usual undo/redo contract,
+// clears the redo stack since it just became a dead branch.
+func pushUndo(op editOp) {
+	undoStack = append(undoStack, op)
+	redoStack = nil
+}
+
+// performUndo reverts the most recent local edit and broadcasts the
+// inverse as a normal CRDT operation so peers converge to the same state.
+func performUndo(conn Transport) {
+	if len(undoStack) == 0 {
+		return
+	}
+	op := undoStack[len(undoStack)-1]
+	undoStack = undoStack[:len(undoStack)-1]
+
+	applyUndoRedo(op.undoType, op, op.before, conn)
+	redoStack = append(redoStack, op)
+}
+
+// performRedo re-applies the most recently undone local edit.
+func performRedo(conn Transport) {
+	if len(redoStack) == 0 {
+		return
+	}
+	op := redoStack[len(redoStack)-1]
+	redoStack = redoStack[:len(redoStack)-1]
+
+	applyUndoRedo(op.redoType, op, op.after, conn)
+	undoStack = append(undoStack, op)
+}
+
+// applyUndoRedo mutates the local CRDT by ID ("undoInsert" tombstones,
+// "undoDelete" restores), moves the cursor to cursorPos (op.before for
+// undo, op.after for redo — see editOp), and sends the same ID-addressed
+// operation over the wire, so peers apply the identical mutation
+// regardless of how their own positions have since shifted.
+func applyUndoRedo(opType string, op editOp, cursorPos int, conn Transport) {
+	docMu.Lock()
+	switch opType {
+	case "undoInsert":
+		doc.DeleteByID(op.id)
+	case "undoDelete":
+		doc.UndeleteByID(op.id)
+	}
+	e.SetText(crdt.Content(doc))
+	docMu.Unlock()
+	e.SetX(cursorPos)
+
+	msg := commons.Message{Type: "operation", Username: username, Operation: commons.Operation{Type: opType, ID: op.id}}
+	if e.IsConnected {
+		if err := conn.Send(msg); err != nil {
+			e.IsConnected = false
+			e.StatusChan <- "lost connection!"
+		}
+	}
 }
 
 const (
@@ -130,19 +241,23 @@ const (
 	OperationDelete
 )
 
-// performOperation performs a CRDT insert or delete operation on the local document and sends a message over the WebSocket connection.
-func performOperation(opType int, ev termbox.Event, conn *websocket.Conn) {
+// performOperation performs a CRDT insert or delete operation on the local
+// document and queues the resulting op to be sent over conn by
+// scheduleBatchFlush, coalescing it with whatever else arrives within the
+// next batchWindow.
+func performOperation(opType int, r rune, conn Transport) {
 	// Get position and value.
-	ch := string(ev.Ch)
-
-	var msg commons.Message
+	ch := string(r)
 
 	// Modify local state (CRDT) first.
+	docMu.Lock()
+	var op commons.Operation
 	switch opType {
 	case OperationInsert:
-		logger.Infof("LOCAL INSERT: %s at cursor position %v\n", ch, e.Cursor)
+		before := e.CursorPos()
+		logger.Infof("LOCAL INSERT: %s at cursor position %v\n", ch, before)
 
-		text, err := doc.Insert(e.Cursor+1, ch)
+		text, id, err := doc.Insert(before, ch)
 		if err != nil {
 			e.SetText(text)
 			logger.Errorf("CRDT error: %v\n", err)
@@ -150,65 +265,110 @@ func performOperation(opType int, ev termbox.Event, conn *websocket.Conn) {
 		e.SetText(text)
 
 		e.MoveCursor(1, 0)
-		msg = commons.Message{Type: "operation", Operation: commons.Operation{Type: "insert", Position: e.Cursor, Value: ch}}
+		after := e.CursorPos()
+		op = commons.Operation{Type: "insert", Position: after, Value: ch, ID: id}
+		pushUndo(editOp{id: id, before: before, after: after, undoType: "undoInsert", redoType: "undoDelete"})
 
 	case OperationDelete:
-		logger.Infof("LOCAL DELETE: cursor position %v\n", e.Cursor)
+		logger.Infof("LOCAL DELETE: cursor position %v\n", e.CursorPos())
 
-		if e.Cursor-1 < 0 {
-			e.Cursor = 0
+		if e.CursorPos()-1 < 0 {
+			e.SetX(0)
 		}
+		before := e.CursorPos()
 
-		text := doc.Delete(e.Cursor)
+		text, id := doc.Delete(before)
 		e.SetText(text)
 
-		msg = commons.Message{Type: "operation", Operation: commons.Operation{Type: "delete", Position: e.Cursor}}
+		op = commons.Operation{Type: "delete", Position: before, ID: id}
 		e.MoveCursor(-1, 0)
+		pushUndo(editOp{id: id, before: before, after: e.CursorPos(), undoType: "undoDelete", redoType: "undoInsert"})
 	}
+	docMu.Unlock()
 
-	// Send the message.
-	if e.IsConnected {
-		err := conn.WriteJSON(msg)
-		if err != nil {
-			e.IsConnected = false
-			e.StatusChan <- "lost connection!"
-		}
+	batchMu.Lock()
+	pendingOps = append(pendingOps, op)
+	scheduleBatchFlush(conn)
+	batchMu.Unlock()
+}
+
+// scheduleBatchFlush arms a flush batchWindow after the first op of a new
+// burst, and leaves it running for every op that arrives before it fires.
+// This throttles rather than debounces: sustained typing still gets a
+// flush roughly every batchWindow instead of being pushed back
+// indefinitely by each new keystroke. Callers must hold batchMu.
+func scheduleBatchFlush(conn Transport) {
+	if batchTimer != nil {
+		return
 	}
+	batchTimer = time.AfterFunc(batchWindow, func() {
+		flushBatch(conn)
+	})
 }
 
-// getTermboxChan returns a channel of termbox Events repeatedly waiting on user input.
-func getTermboxChan() chan termbox.Event {
-	termboxChan := make(chan termbox.Event)
+// flushBatch sends every operation queued since the last flush, as a
+// single "operation" message when there's only one (so a peer that never
+// negotiated BatchOperationMessage still understands it) or as one
+// BatchOperationMessage otherwise. It falls back to queueOffline, same as
+// performOperation always did, if the send fails.
+func flushBatch(conn Transport) {
+	batchMu.Lock()
+	ops := pendingOps
+	pendingOps = nil
+	batchTimer = nil
+	batchMu.Unlock()
+
+	if len(ops) == 0 {
+		return
+	}
 
-	go func() {
-		for {
-			termboxChan <- termbox.PollEvent()
-		}
-	}()
+	var msg commons.Message
+	if len(ops) == 1 {
+		msg = commons.Message{Type: "operation", Operation: ops[0]}
+	} else {
+		msg = commons.Message{Type: commons.BatchOperationMessage, Operations: ops, BaseClock: ops[0].ID.Clock}
+	}
 
-	return termboxChan
+	if e.IsConnected {
+		if err := conn.Send(msg); err != nil {
+			e.IsConnected = false
+			e.StatusChan <- "lost connection! buffering edits until reconnected..."
+			queueOffline(sessionID, msg)
+			go reconnectLoop(sessionID, serverURL)
+		}
+	} else {
+		queueOffline(sessionID, msg)
+	}
 }
 
 // handleMsg updates the CRDT document with the contents of the message.
-func handleMsg(msg commons.Message, conn *websocket.Conn) {
+func handleMsg(msg commons.Message, conn Transport) {
 	switch msg.Type {
 	case commons.DocSyncMessage:
 		logger.Infof("DOCSYNC RECEIVED, updating local doc %+v\n", msg.Document)
+		docMu.Lock()
 		doc = msg.Document
 		e.SetText(crdt.Content(doc))
+		docMu.Unlock()
 
 	case commons.DocReqMessage:
 		logger.Infof("DOCREQ RECEIVED, sending local document to %v\n", msg.ID)
+		docMu.Lock()
 		docMsg := commons.Message{Type: commons.DocSyncMessage, Document: doc, ID: msg.ID}
-		_ = conn.WriteJSON(&docMsg)
+		docMu.Unlock()
+		_ = conn.Send(docMsg)
 
 	case commons.SiteIDMessage:
-		siteID, err := strconv.Atoi(msg.Text)
-		if err != nil {
-			logger.Errorf("failed to set siteID, err: %v\n", err)
+		applySiteID(msg, conn)
+		logger.Infof("SITE ID %v", crdt.SiteID)
+
+	case commons.BatchOperationMessage:
+		logger.Infof("BATCH RECEIVED: %d op(s), base clock %v\n", len(msg.Operations), msg.BaseClock)
+		docMu.Lock()
+		for _, op := range msg.Operations {
+			applyRemoteOperation(op, msg.Username)
 		}
-		crdt.SiteID = siteID
-		logger.Infof("SITE ID %v, INTENDED SITE ID: %v", crdt.SiteID, siteID)
+		docMu.Unlock()
 
 	case commons.JoinMessage:
 		e.StatusChan <- fmt.Sprintf("%s has joined the session!", msg.Username)
@@ -218,57 +378,127 @@ func handleMsg(msg commons.Message, conn *websocket.Conn) {
 		e.Users = strings.Split(msg.Text, ",")
 		e.StatusMu.Unlock()
 
-	default:
-		switch msg.Operation.Type {
-		case "insert":
-			_, err := doc.Insert(msg.Operation.Position, msg.Operation.Value)
-			if err != nil {
-				logger.Errorf("failed to insert, err: %v\n", err)
-			}
+	case commons.ChatMessage:
+		// Chat is transient: it never touches doc, so it can't end up in a
+		// saved file.
+		e.AppendChatLine(fmt.Sprintf("%s: %s", msg.Username, msg.Text))
 
-			e.SetText(crdt.Content(doc))
-			if msg.Operation.Position-1 <= e.Cursor {
-				e.MoveCursor(len(msg.Operation.Value), 0)
-			}
-			logger.Infof("REMOTE INSERT: %s at position %v\n", msg.Operation.Value, msg.Operation.Position)
+	case commons.PresenceMessage:
+		p := msg.Presence
+		if p.SiteID == crdt.SiteID {
+			break
+		}
 
-		case "delete":
-			_ = doc.Delete(msg.Operation.Position)
-			e.SetText(crdt.Content(doc))
-			if msg.Operation.Position-1 <= e.Cursor {
-				e.MoveCursor(-len(msg.Operation.Value), 0)
-			}
-			logger.Infof("REMOTE DELETE: position %v\n", msg.Operation.Position)
+		docMu.Lock()
+		head := crdt.PositionAt(doc, p.HeadID)
+		anchor := crdt.PositionAt(doc, p.AnchorID)
+		docMu.Unlock()
+		if head == -1 {
+			// The anchor was deleted by a concurrent edit; drop the stale
+			// presence update rather than render it at the wrong spot.
+			break
+		}
+		if anchor == -1 {
+			anchor = head
 		}
+
+		e.SetRemoteCursor(p.SiteID, editor.RemoteCursor{
+			Username: p.Username,
+			Position: head,
+			Anchor:   anchor,
+			Color:    p.Color,
+		})
+
+	default:
+		docMu.Lock()
+		applyRemoteOperation(msg.Operation, msg.Username)
+		docMu.Unlock()
 	}
 
+	docMu.Lock()
 	printDoc(doc)
+	docMu.Unlock()
 	e.SendDraw()
 }
 
-// getMsgChan returns a message channel that repeatedly reads from a websocket connection.
-func getMsgChan(conn *websocket.Conn) chan commons.Message {
+// applyRemoteOperation applies a single remote Operation to the local
+// document. It's shared by handleMsg's default case (a lone "operation"
+// message) and its BatchOperationMessage case (several ops applied in
+// order), so both paths converge identically regardless of whether the
+// sender happened to coalesce them. Callers must hold docMu.
+func applyRemoteOperation(op commons.Operation, actor string) {
+	switch op.Type {
+	case "undoInsert":
+		value, _ := crdt.ValueOf(doc, op.ID)
+		position := crdt.PositionAt(doc, op.ID)
+
+		_, _ = doc.DeleteByID(op.ID)
+		e.SetText(crdt.Content(doc))
+		if position != -1 && position-1 <= e.CursorPos() {
+			e.MoveCursor(-len(value), 0)
+		}
+		if actor != "" {
+			e.StatusChan <- fmt.Sprintf("%s undid an edit", actor)
+		}
+
+	case "undoDelete":
+		_, _ = doc.UndeleteByID(op.ID)
+		e.SetText(crdt.Content(doc))
+
+		value, _ := crdt.ValueOf(doc, op.ID)
+		position := crdt.PositionAt(doc, op.ID)
+		if position != -1 && position-1 <= e.CursorPos() {
+			e.MoveCursor(len(value), 0)
+		}
+		if actor != "" {
+			e.StatusChan <- fmt.Sprintf("%s undid an edit", actor)
+		}
+
+	case "insert":
+		// op.Position is the inserted character's 1-based visible position.
+		_, _, err := doc.Insert(op.Position-1, op.Value)
+		if err != nil {
+			logger.Errorf("failed to insert, err: %v\n", err)
+		}
+
+		e.SetText(crdt.Content(doc))
+		if op.Position-1 <= e.CursorPos() {
+			e.MoveCursor(len(op.Value), 0)
+		}
+		logger.Infof("REMOTE INSERT: %s at position %v\n", op.Value, op.Position)
+
+	case "delete":
+		_, _ = doc.Delete(op.Position)
+		e.SetText(crdt.Content(doc))
+		if op.Position-1 <= e.CursorPos() {
+			e.MoveCursor(-len(op.Value), 0)
+		}
+		logger.Infof("REMOTE DELETE: position %v\n", op.Position)
+	}
+}
+
+// getMsgChan returns a message channel that repeatedly reads from conn,
+// regardless of whether it's backed by a WebSocket or SSE. It survives
+// reconnects: when the transport's stream ends, it blocks on
+// reconnectLoop and then resumes reading from whatever Transport resync
+// swapped into activeConn, instead of leaving the caller listening on a
+// channel that will never receive again.
+func getMsgChan(conn Transport) chan commons.Message {
 	messageChan := make(chan commons.Message)
 	go func() {
 		for {
-			var msg commons.Message
-
-			// Read message.
-			err := conn.ReadJSON(&msg)
-			if err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					logger.Errorf("websocket error: %v", err)
-				}
-				e.IsConnected = false
-				e.StatusChan <- "lost connection!"
-				break
+			for msg := range conn.Recv() {
+				logger.Infof("message received: %+v\n", msg)
+				messageChan <- msg
 			}
 
-			logger.Infof("message received: %+v\n", msg)
-
-			// send message through channel
-			messageChan <- msg
-
+			// The transport's stream ended, which only happens on a
+			// connection error; everything else is bundled as ordinary
+			// Messages.
+			e.IsConnected = false
+			e.StatusChan <- "lost connection! buffering edits until reconnected..."
+			reconnectLoop(sessionID, serverURL)
+			conn = activeConn
 		}
 	}()
 	return messageChan
@@ -296,10 +526,3 @@ func handleStatusMsg() {
 	}
 
 }
-
-func drawLoop() {
-	for {
-		<-e.DrawChan
-		e.Draw()
-	}
-}
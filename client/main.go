@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/burntcarrot/pairpad/commons"
+	"github.com/burntcarrot/pairpad/crdt"
+	"github.com/burntcarrot/pairpad/editor"
+	"github.com/sirupsen/logrus"
+)
+
+// doc is the client's local replica of the shared document. It's mutated in
+// place by CRDT operations and replaced wholesale on a DocSyncMessage.
+var doc crdt.Document
+
+// docMu guards doc. It's touched from the UI goroutine (local edits,
+// undo/redo, save/load), the remote-message consumer goroutine (applied
+// remote ops, doc sync), the presenceTimer goroutine (broadcastPresence),
+// and the reconnect goroutine (resync), so every access goes through it.
+var docMu sync.Mutex
+
+// e holds all client-side UI state; every file in this package reads and
+// writes it directly rather than threading it through as a parameter.
+var e *editor.Editor
+
+// logger writes diagnostics to pairpad.log instead of stdout, since stdout
+// is owned by gocui while the TUI is running.
+var logger *logrus.Logger
+
+// username, sessionID, serverURL, fileName, and transportKind are parsed
+// from flags once in main() and read by the rest of the package for the
+// life of the process.
+var (
+	username      string
+	sessionID     string
+	serverURL     string
+	fileName      string
+	transportKind string
+)
+
+// printDoc logs doc's visible content at debug level, for troubleshooting
+// divergence between peers.
+func printDoc(doc crdt.Document) {
+	logger.Debugf("DOCUMENT: %q", crdt.Content(doc))
+}
+
+// presencePalette lists the caret colors assigned to sites, in the same
+// vocabulary editor.ansiReverse knows how to render.
+var presencePalette = []string{"red", "green", "yellow", "blue", "magenta", "cyan"}
+
+// presenceColorFor deterministically maps a site ID to a caret color so
+// peers agree on each other's colors without negotiating them.
+func presenceColorFor(siteID int) string {
+	if siteID <= 0 {
+		return "cyan"
+	}
+	return presencePalette[siteID%len(presencePalette)]
+}
+
+// applySiteID parses a SiteIDMessage's site ID and protocol version,
+// assigning crdt.SiteID/localPresenceColor and enabling binary framing on
+// conn if both peers advertise commons.ProtocolVersion >= 2. It's shared
+// by main's initial handshake and resync's post-reconnect handshake,
+// since a freshly dialed connection sends the exact same SiteIDMessage
+// either way.
+func applySiteID(msg commons.Message, conn Transport) {
+	siteID, err := strconv.Atoi(msg.Text)
+	if err != nil {
+		logger.Errorf("failed to parse siteID, err: %v\n", err)
+	}
+	crdt.SiteID = siteID
+	localPresenceColor = presenceColorFor(siteID)
+
+	if msg.ProtocolVersion >= commons.ProtocolVersion {
+		if wst, ok := conn.(*WSTransport); ok {
+			wst.EnableBinaryFraming()
+		}
+	}
+}
+
+func main() {
+	flag.StringVar(&serverURL, "server", "ws://localhost:8080/ws", "address of the pairpad server")
+	flag.StringVar(&username, "username", "", "display name shown to peers")
+	flag.StringVar(&sessionID, "session", "default", "session to join")
+	flag.StringVar(&fileName, "file", "", "file to load/save with Ctrl+L/Ctrl+S")
+	flag.StringVar(&transportKind, "transport", "ws", "transport to use: ws or sse")
+	flag.Parse()
+
+	logFile, err := os.OpenFile("pairpad.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Println("failed to open log file:", err)
+		os.Exit(1)
+	}
+	defer logFile.Close()
+
+	logger = logrus.New()
+	logger.SetOutput(logFile)
+
+	conn, err := dialTransport(transportKind, serverURL, sessionID)
+	if err != nil {
+		fmt.Println("failed to connect:", err)
+		os.Exit(1)
+	}
+	activeConn = conn
+
+	siteMsg, ok := <-conn.Recv()
+	if !ok {
+		fmt.Println("connection closed before assigning a site ID")
+		os.Exit(1)
+	}
+	if siteMsg.Type == commons.SiteIDMessage {
+		applySiteID(siteMsg, conn)
+	}
+
+	if err := conn.Send(commons.Message{Type: commons.JoinMessage, Username: username, ProtocolVersion: commons.ProtocolVersion}); err != nil {
+		logger.Errorf("failed to send join message: %v\n", err)
+	}
+
+	e = editor.NewEditor()
+	e.IsConnected = true
+
+	g, err := gocui.NewGui(gocui.OutputNormal, true)
+	if err != nil {
+		fmt.Println("failed to start UI:", err)
+		os.Exit(1)
+	}
+	defer g.Close()
+
+	e.Gui = g
+	g.Cursor = true
+	g.SetManagerFunc(layout)
+
+	if err := setKeybindings(g); err != nil {
+		fmt.Println("failed to set keybindings:", err)
+		os.Exit(1)
+	}
+
+	msgChan := getMsgChan(conn)
+	go func() {
+		for msg := range msgChan {
+			handleMsg(msg, activeConn)
+		}
+	}()
+
+	go handleStatusMsg()
+	go drawLoop()
+
+	if err := g.MainLoop(); err != nil && err != gocui.ErrQuit {
+		logger.Errorf("main loop exited: %v\n", err)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/burntcarrot/pairpad/commons"
+	"github.com/burntcarrot/pairpad/crdt"
+)
+
+// reconnectBackoffMax caps how long reconnectLoop waits between dial
+// attempts, so a long outage doesn't turn into an hours-long wait once
+// the server comes back.
+const reconnectBackoffMax = 30 * time.Second
+
+// walPath returns the on-disk path used to buffer operations generated by
+// sessionID while disconnected from the server.
+func walPath(sessionID string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, ".pairpad")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("pending-%s.log", sessionID)), nil
+}
+
+// queueOffline appends msg to the local write-ahead log so a locally
+// generated edit survives a crash or restart while disconnected, and can
+// be replayed once the server is reachable again.
+func queueOffline(sessionID string, msg commons.Message) {
+	path, err := walPath(sessionID)
+	if err != nil {
+		logger.Errorf("failed to resolve WAL path: %v", err)
+		return
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		logger.Errorf("failed to open WAL: %v", err)
+		return
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		logger.Errorf("failed to marshal pending op: %v", err)
+		return
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		logger.Errorf("failed to append to WAL: %v", err)
+	}
+}
+
+// loadPendingOps reads back every message queued by queueOffline, in the
+// order they were written.
+func loadPendingOps(sessionID string) []commons.Message {
+	path, err := walPath(sessionID)
+	if err != nil {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var pending []commons.Message
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg commons.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			logger.Errorf("failed to parse pending op: %v", err)
+			continue
+		}
+		pending = append(pending, msg)
+	}
+	return pending
+}
+
+// clearPendingOps removes the WAL after its contents have been
+// successfully replayed.
+func clearPendingOps(sessionID string) {
+	path, err := walPath(sessionID)
+	if err != nil {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		logger.Errorf("failed to clear WAL: %v", err)
+	}
+}
+
+// reconnectLoop dials serverURL with exponential backoff until it
+// succeeds and resync reports success, replaying any operations queued
+// while disconnected. It returns once reconnection succeeds (or the
+// editor has been reconnected by another caller in the meantime); a dial
+// that succeeds but whose resync then fails (e.g. the connection drops
+// again mid-resync) is treated as a failed attempt and retried with the
+// same backoff, rather than stranding the client offline forever.
+func reconnectLoop(sessionID, serverURL string) {
+	backoff := time.Second
+
+	for {
+		if e.IsConnected {
+			return
+		}
+
+		e.StatusChan <- fmt.Sprintf("reconnecting in %s...", backoff)
+		time.Sleep(backoff)
+
+		conn, err := dialTransport(transportKind, serverURL, sessionID)
+		if err != nil {
+			logger.Errorf("reconnect attempt failed: %v", err)
+			backoff *= 2
+			if backoff > reconnectBackoffMax {
+				backoff = reconnectBackoffMax
+			}
+			continue
+		}
+
+		e.StatusChan <- "reconnected! syncing document..."
+		if !resync(sessionID, conn) {
+			conn.Close()
+			backoff *= 2
+			if backoff > reconnectBackoffMax {
+				backoff = reconnectBackoffMax
+			}
+			continue
+		}
+		return
+	}
+}
+
+// resyncMaxMessages bounds how many messages resync will read while
+// waiting for the DocSyncMessage it requested. A freshly dialed
+// connection's first message is always a SiteIDMessage handshake (see
+// main.go), not the doc sync, so resync has to keep reading past it; the
+// cap just stops a server that never answers from wedging reconnectLoop
+// forever.
+const resyncMaxMessages = 8
+
+// resync requests the authoritative document from the server, merges it
+// with local state via the CRDT's RGA merge, then replays any operations
+// queued while this client was offline. conn is a newly dialed
+// connection, so resync reads messages off it the same way main() reads
+// off the original one: the first is a SiteIDMessage handshake (handled
+// via applySiteID), and only then does the requested DocSyncMessage
+// arrive. On success it swaps conn into activeConn so the message loop
+// picks it up and reports true; on any failure it reports false without
+// touching activeConn or e.IsConnected, so reconnectLoop knows to keep
+// retrying instead of leaving the client stranded offline.
+func resync(sessionID string, conn Transport) bool {
+	if err := conn.Send(commons.Message{Type: commons.DocReqMessage}); err != nil {
+		logger.Errorf("failed to request doc during resync: %v", err)
+		return false
+	}
+
+	synced := false
+	for i := 0; !synced && i < resyncMaxMessages; i++ {
+		msg, ok := <-conn.Recv()
+		if !ok {
+			logger.Errorf("failed to read doc sync during resync: connection closed")
+			return false
+		}
+
+		switch msg.Type {
+		case commons.SiteIDMessage:
+			applySiteID(msg, conn)
+		case commons.DocSyncMessage:
+			docMu.Lock()
+			doc = crdt.Merge(doc, msg.Document)
+			e.SetText(crdt.Content(doc))
+			docMu.Unlock()
+			synced = true
+		}
+	}
+	if !synced {
+		logger.Errorf("gave up waiting for doc sync during resync after %d messages", resyncMaxMessages)
+		return false
+	}
+
+	pending := loadPendingOps(sessionID)
+	for _, msg := range pending {
+		if err := conn.Send(msg); err != nil {
+			logger.Errorf("failed to replay pending op: %v", err)
+			return false
+		}
+	}
+	clearPendingOps(sessionID)
+
+	activeConn = conn
+	e.IsConnected = true
+	e.StatusChan <- fmt.Sprintf("resynced, replayed %d queued edit(s)", len(pending))
+	return true
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/burntcarrot/pairpad/commons"
+)
+
+// SSETransport talks to the server over plain HTTP: incoming operations
+// arrive as a `text/event-stream` GET, and outgoing ones are POSTed as
+// JSON. It exists alongside WSTransport for networks that block WebSocket
+// upgrades but allow long-lived HTTP responses.
+type SSETransport struct {
+	baseURL   string
+	sessionID string
+	client    *http.Client
+
+	msgChan chan commons.Message
+
+	mu    sync.Mutex
+	since int // highest commons.Message.Seq observed so far
+}
+
+// NewSSETransport opens the `/events` stream for sessionID and starts the
+// background loop feeding Recv().
+func NewSSETransport(baseURL, sessionID string) (*SSETransport, error) {
+	t := &SSETransport{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		sessionID: sessionID,
+		client:    &http.Client{},
+		msgChan:   make(chan commons.Message),
+	}
+
+	resp, err := t.client.Get(t.eventsURL())
+	if err != nil {
+		return nil, err
+	}
+
+	go t.readLoop(resp)
+	return t, nil
+}
+
+func (t *SSETransport) eventsURL() string {
+	t.mu.Lock()
+	since := t.since
+	t.mu.Unlock()
+	return fmt.Sprintf("%s/events?session=%s&since=%d", t.baseURL, t.sessionID, since)
+}
+
+// readLoop parses the `text/event-stream` body, one `data: <json>` line
+// per commons.Message, blank lines separating events.
+func (t *SSETransport) readLoop(resp *http.Response) {
+	defer close(t.msgChan)
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !bytes.HasPrefix(line, []byte("data: ")) {
+			continue
+		}
+
+		var msg commons.Message
+		if err := json.Unmarshal(bytes.TrimPrefix(line, []byte("data: ")), &msg); err != nil {
+			logger.Errorf("sse: failed to decode event: %v", err)
+			continue
+		}
+
+		t.mu.Lock()
+		if msg.Seq > t.since {
+			t.since = msg.Seq
+		}
+		t.mu.Unlock()
+
+		t.msgChan <- msg
+	}
+	if err := scanner.Err(); err != nil {
+		logger.Errorf("sse: stream error: %v", err)
+	}
+}
+
+// Send POSTs msg to /ops as JSON.
+func (t *SSETransport) Send(msg commons.Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.client.Post(t.baseURL+"/ops?session="+t.sessionID, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("sse: server rejected op: %s", resp.Status)
+	}
+	return nil
+}
+
+// Recv returns the channel fed by readLoop.
+func (t *SSETransport) Recv() <-chan commons.Message {
+	return t.msgChan
+}
+
+// Close is a no-op for SSE: there's no persistent connection to tear down
+// beyond the event stream, which readLoop already releases.
+func (t *SSETransport) Close() error {
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import "github.com/burntcarrot/pairpad/commons"
+
+// Transport abstracts how the client exchanges commons.Messages with the
+// server, so the editor loop doesn't need to care whether it's talking
+// over a WebSocket or plain HTTP/SSE.
+type Transport interface {
+	// Send delivers msg to the server.
+	Send(msg commons.Message) error
+
+	// Recv returns the channel of messages arriving from the server. The
+	// channel is closed when the transport's connection is lost.
+	Recv() <-chan commons.Message
+
+	// Close releases any resources held by the transport.
+	Close() error
+}
+
+// dialTransport connects to serverURL using the given transport kind
+// ("ws" or "sse", selected by the --transport flag) and returns a ready
+// Transport.
+func dialTransport(kind, serverURL, sessionID string) (Transport, error) {
+	switch kind {
+	case "sse":
+		return NewSSETransport(serverURL, sessionID)
+	default:
+		return NewWSTransport(serverURL)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/burntcarrot/pairpad/editor"
+)
+
+// sidebarWidth is how many columns the user-list sidebar takes on the
+// right edge of the screen.
+const sidebarWidth = 20
+
+// layout arranges the editor, sidebar, status, and chat views. It's
+// registered as the gocui.Gui's Layout function in main().
+func layout(g *gocui.Gui) error {
+	maxX, maxY := g.Size()
+
+	if v, err := g.SetView(editor.EditorView, 0, 0, maxX-sidebarWidth-1, maxY-5, 0); err != nil {
+		if !errors.Is(err, gocui.ErrUnknownView) {
+			return err
+		}
+		v.Title = " pairpad "
+		v.Editable = true
+		v.Wrap = true
+		v.Editor = pairpadEditor{}
+		if _, err := g.SetCurrentView(editor.EditorView); err != nil {
+			return err
+		}
+	}
+
+	if v, err := g.SetView(editor.SidebarView, maxX-sidebarWidth, 0, maxX-1, maxY-5, 0); err != nil {
+		if !errors.Is(err, gocui.ErrUnknownView) {
+			return err
+		}
+		v.Title = " users "
+	}
+
+	if v, err := g.SetView(editor.ChatView, 0, maxY-4, maxX-1, maxY-2, 0); err != nil {
+		if !errors.Is(err, gocui.ErrUnknownView) {
+			return err
+		}
+		v.Title = " chat (ctrl+g) "
+		v.Editable = true
+		v.Editor = pairpadChatEditor{}
+	}
+
+	if v, err := g.SetView(editor.StatusView, 0, maxY-1, maxX-1, maxY+1, 0); err != nil {
+		if !errors.Is(err, gocui.ErrUnknownView) {
+			return err
+		}
+		v.Frame = false
+	}
+
+	return nil
+}
+
+// quit stops the gocui main loop, which unwinds main() and exits pairpad.
+// It flushes any operations still waiting out their batchWindow first, so
+// quitting right after a keystroke can't silently drop that edit.
+func quit(g *gocui.Gui, v *gocui.View) error {
+	flushBatch(activeConn)
+	return gocui.ErrQuit
+}
+
+// setKeybindings registers the action keys that don't mutate the buffer
+// directly (save/load/undo/redo/select-all/quit). Text-mutating keys
+// (characters, backspace/delete, tab, enter, arrows) are handled by
+// pairpadEditor.Edit instead, since gocui routes those through the
+// current view's Editor rather than individual keybindings.
+func setKeybindings(g *gocui.Gui) error {
+	bindings := []struct {
+		key gocui.Key
+		mod gocui.Modifier
+		fn  func(g *gocui.Gui, v *gocui.View) error
+	}{
+		{gocui.KeyCtrlC, gocui.ModNone, quit},
+		{gocui.KeyEsc, gocui.ModNone, quit},
+		{gocui.KeyCtrlS, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+			_ = saveToFile()
+			return nil
+		}},
+		{gocui.KeyCtrlL, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+			_ = loadFromFile(activeConn)
+			return nil
+		}},
+		{gocui.KeyCtrlA, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+			e.SetSelectionAnchor(0)
+			e.SetX(len(e.Text))
+			schedulePresenceBroadcast(activeConn)
+			return nil
+		}},
+		{gocui.KeyCtrlZ, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+			performUndo(activeConn)
+			return nil
+		}},
+		{gocui.KeyCtrlY, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+			performRedo(activeConn)
+			return nil
+		}},
+	}
+
+	for _, b := range bindings {
+		if err := g.SetKeybinding(editor.EditorView, b.key, b.mod, b.fn); err != nil {
+			return err
+		}
+	}
+
+	if err := g.SetKeybinding(editor.EditorView, gocui.KeyCtrlG, gocui.ModNone, enterChatMode); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding(editor.ChatView, gocui.KeyCtrlG, gocui.ModNone, exitChatMode); err != nil {
+		return err
+	}
+	return nil
+}
+
+// enterChatMode switches focus to the chat view so pairpadChatEditor starts
+// receiving keystrokes instead of the document editor.
+func enterChatMode(g *gocui.Gui, v *gocui.View) error {
+	e.ChatMu.Lock()
+	e.InChatMode = true
+	e.ChatMu.Unlock()
+
+	if _, err := g.SetCurrentView(editor.ChatView); err != nil {
+		return err
+	}
+	e.SendDraw()
+	return nil
+}
+
+// exitChatMode drops any partially composed chat line and returns focus to
+// the document editor.
+func exitChatMode(g *gocui.Gui, v *gocui.View) error {
+	e.ChatMu.Lock()
+	e.InChatMode = false
+	e.ChatInput = nil
+	e.ChatMu.Unlock()
+
+	if _, err := g.SetCurrentView(editor.EditorView); err != nil {
+		return err
+	}
+	e.SendDraw()
+	return nil
+}
+
+// pairpadEditor implements gocui.Editor, replacing the old termbox switch
+// statement for every key that mutates the buffer: character insertion,
+// backspace/delete, tab, enter, space, and plain/shift-held arrow
+// movement. Action keys that don't touch the buffer are registered as
+// ordinary keybindings in setKeybindings instead.
+type pairpadEditor struct{}
+
+func (pairpadEditor) Edit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	conn := activeConn
+
+	switch key {
+	case gocui.KeyArrowLeft:
+		startOrClearSelection(mod&gocui.ModShift != 0)
+		e.MoveCursor(-1, 0)
+	case gocui.KeyArrowRight:
+		startOrClearSelection(mod&gocui.ModShift != 0)
+		e.MoveCursor(1, 0)
+	case gocui.KeyArrowUp:
+		startOrClearSelection(mod&gocui.ModShift != 0)
+		e.MoveCursor(0, -1)
+	case gocui.KeyArrowDown:
+		startOrClearSelection(mod&gocui.ModShift != 0)
+		e.MoveCursor(0, 1)
+	case gocui.KeyHome:
+		e.SetX(0)
+	case gocui.KeyEnd:
+		e.SetX(len(e.Text))
+	case gocui.KeyBackspace, gocui.KeyBackspace2, gocui.KeyDelete:
+		performOperation(OperationDelete, 0, conn)
+	case gocui.KeyTab:
+		for i := 0; i < 4; i++ {
+			performOperation(OperationInsert, ' ', conn)
+		}
+	case gocui.KeyEnter:
+		performOperation(OperationInsert, '\n', conn)
+	case gocui.KeySpace:
+		performOperation(OperationInsert, ' ', conn)
+	default:
+		if ch != 0 {
+			performOperation(OperationInsert, ch, conn)
+		}
+	}
+
+	schedulePresenceBroadcast(conn)
+	e.SendDraw()
+}
+
+// pairpadChatEditor implements gocui.Editor for the chat input line: it
+// accumulates runes into e.ChatInput and sends the composed line as a
+// ChatMessage on Enter. Ctrl+G (exitChatMode) is bound separately to leave
+// chat mode without sending.
+type pairpadChatEditor struct{}
+
+func (pairpadChatEditor) Edit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	switch key {
+	case gocui.KeyBackspace, gocui.KeyBackspace2:
+		e.ChatMu.Lock()
+		if len(e.ChatInput) > 0 {
+			e.ChatInput = e.ChatInput[:len(e.ChatInput)-1]
+		}
+		e.ChatMu.Unlock()
+
+	case gocui.KeyEnter:
+		e.ChatMu.Lock()
+		text := string(e.ChatInput)
+		e.ChatInput = nil
+		e.ChatMu.Unlock()
+
+		sendChatMessage(text, activeConn)
+
+	case gocui.KeySpace:
+		e.ChatMu.Lock()
+		e.ChatInput = append(e.ChatInput, ' ')
+		e.ChatMu.Unlock()
+
+	default:
+		if ch != 0 {
+			e.ChatMu.Lock()
+			e.ChatInput = append(e.ChatInput, ch)
+			e.ChatMu.Unlock()
+		}
+	}
+
+	e.SendDraw()
+}
+
+// drawLoop repaints every view whenever e.DrawChan fires.
+func drawLoop() {
+	for range e.DrawChan {
+		_ = e.Render()
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+
+	"github.com/burntcarrot/pairpad/commons"
+	"github.com/gorilla/websocket"
+)
+
+// WSTransport is the original gorilla/websocket-backed Transport. It
+// speaks plain JSON text frames until both peers have negotiated
+// commons.ProtocolVersion >= 2 (see EnableBinaryFraming), at which point it
+// switches outgoing frames to EncodeBinary's gob format.
+// Incoming frames are decoded by their gorilla/websocket frame type, so a
+// peer that never negotiates stays on JSON without any extra bookkeeping.
+type WSTransport struct {
+	conn    *websocket.Conn
+	msgChan chan commons.Message
+
+	// binary is read/written from different goroutines (applySiteID enables
+	// it after a handshake, on whichever goroutine received the
+	// SiteIDMessage; Send reads it from every sending goroutine), hence
+	// atomic rather than a plain bool.
+	binary int32
+
+	// writeMu serializes Send: gorilla/websocket allows only one
+	// concurrent writer per connection, but Send is called from the UI
+	// goroutine (edits, chat, undo/redo, doc load) and from the
+	// independent presenceTimer/batchTimer goroutines.
+	writeMu sync.Mutex
+}
+
+// NewWSTransport dials serverURL over WebSocket and starts the background
+// read loop feeding Recv().
+func NewWSTransport(serverURL string) (*WSTransport, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(serverURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &WSTransport{conn: conn, msgChan: make(chan commons.Message)}
+	go t.readLoop()
+	return t, nil
+}
+
+func (t *WSTransport) readLoop() {
+	defer close(t.msgChan)
+	for {
+		frameType, data, err := t.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				logger.Errorf("websocket error: %v", err)
+			}
+			return
+		}
+
+		var msg commons.Message
+		if frameType == websocket.BinaryMessage {
+			msg, err = commons.DecodeBinary(data)
+		} else {
+			err = json.Unmarshal(data, &msg)
+		}
+		if err != nil {
+			logger.Errorf("websocket: failed to decode frame: %v", err)
+			continue
+		}
+
+		t.msgChan <- msg
+	}
+}
+
+// EnableBinaryFraming switches future Send calls to commons.EncodeBinary's
+// binary framing. It's called once applySiteID (main.go) sees a peer advertise
+// commons.ProtocolVersion >= 2 in its SiteIDMessage.
+func (t *WSTransport) EnableBinaryFraming() {
+	atomic.StoreInt32(&t.binary, 1)
+}
+
+// Send writes msg to the WebSocket connection, as binary-framed gob once
+// EnableBinaryFraming has been called, or JSON otherwise. Writes are
+// serialized by writeMu since the underlying websocket.Conn only supports
+// one writer at a time.
+func (t *WSTransport) Send(msg commons.Message) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if atomic.LoadInt32(&t.binary) == 1 {
+		framed, err := commons.EncodeBinary(msg)
+		if err != nil {
+			return err
+		}
+		return t.conn.WriteMessage(websocket.BinaryMessage, framed)
+	}
+	return t.conn.WriteJSON(&msg)
+}
+
+// Recv returns the channel fed by readLoop.
+func (t *WSTransport) Recv() <-chan commons.Message {
+	return t.msgChan
+}
+
+// Close closes the underlying WebSocket connection.
+func (t *WSTransport) Close() error {
+	return t.conn.Close()
+}
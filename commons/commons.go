@@ -0,0 +1,133 @@
+// Package commons contains the message and operation types shared between
+// the pairpad client and server.
+package commons
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/burntcarrot/pairpad/crdt"
+)
+
+// ProtocolVersion is the highest wire protocol version this build speaks.
+// It's advertised in the initial SiteIDMessage so peers can agree on
+// whether to use binary framing and BatchOperationMessage; a peer that
+// doesn't understand either simply ignores the field and keeps speaking
+// plain per-operation JSON, which every version supports.
+const ProtocolVersion = 2
+
+// MessageType identifies the purpose of a Message.
+type MessageType string
+
+const (
+	// DocSyncMessage carries a full document so a client can converge to it.
+	DocSyncMessage MessageType = "docSync"
+
+	// DocReqMessage asks a peer (usually the server) to send back the
+	// authoritative document.
+	DocReqMessage MessageType = "docReq"
+
+	// SiteIDMessage assigns a site ID to a newly connected client.
+	SiteIDMessage MessageType = "siteID"
+
+	// JoinMessage announces that a user has joined the session.
+	JoinMessage MessageType = "join"
+
+	// UsersMessage carries the comma-separated list of users in the session.
+	UsersMessage MessageType = "users"
+
+	// PresenceMessage broadcasts a user's cursor and selection so peers can
+	// render remote carets and highlights.
+	PresenceMessage MessageType = "presence"
+
+	// ChatMessage carries a line of in-session chat. It's transient: never
+	// applied to the CRDT document, so it never ends up in a saved file.
+	ChatMessage MessageType = "chat"
+
+	// BatchOperationMessage carries several Operations coalesced from a
+	// short burst of local edits (see Message.Operations), so a paste or a
+	// held key doesn't cost one round trip per character.
+	BatchOperationMessage MessageType = "batchOperation"
+)
+
+// Presence carries a remote user's cursor/selection, anchored to CRDT
+// element IDs so it stays put when peers insert or delete text around it.
+type Presence struct {
+	SiteID   int             `json:"siteID"`
+	Username string          `json:"username"`
+	AnchorID crdt.Identifier `json:"anchorID"`
+	HeadID   crdt.Identifier `json:"headID"`
+	Color    string          `json:"color"`
+}
+
+// Operation describes a single CRDT mutation sent over the wire.
+//
+// Type is usually "insert" or "delete", addressed by Position. Undo/redo
+// instead send "undoInsert" or "undoDelete", which are addressed by ID:
+// "undoInsert" tombstones the named element and "undoDelete" restores it,
+// so replay stays correct even if Position has since shifted.
+type Operation struct {
+	Type     string          `json:"type"`
+	Position int             `json:"position"`
+	Value    string          `json:"value"`
+	ID       crdt.Identifier `json:"id,omitempty"`
+}
+
+// Message is the envelope exchanged between clients and the server.
+type Message struct {
+	Type      MessageType   `json:"type"`
+	Text      string        `json:"text,omitempty"`
+	Username  string        `json:"username,omitempty"`
+	ID        int           `json:"id,omitempty"`
+	Operation Operation     `json:"operation,omitempty"`
+	Document  crdt.Document `json:"document,omitempty"`
+	Presence  Presence      `json:"presence,omitempty"`
+
+	// Seq is a server-assigned, monotonically increasing sequence number.
+	// SSETransport records the highest Seq it has received, but sends it as
+	// the `since` cursor only when it opens its event stream, which happens
+	// once per transport; a reconnect dials a new SSETransport, which starts
+	// again from since=0 and relies on the DocReqMessage resync instead.
+	Seq int `json:"seq,omitempty"`
+
+	// Timestamp is a Unix timestamp used by ChatMessage to order and
+	// display chat lines. It's meaningless for every other MessageType.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
+	// ProtocolVersion is set on SiteIDMessage to advertise the highest
+	// ProtocolVersion the sender speaks, so the other side knows whether
+	// it's safe to use BatchOperationMessage and binary framing. Absent
+	// (zero) means "JSON, one operation at a time" — the original protocol.
+	ProtocolVersion int `json:"protocolVersion,omitempty"`
+
+	// Operations carries the coalesced ops of a BatchOperationMessage, in
+	// application order.
+	Operations []Operation `json:"operations,omitempty"`
+
+	// BaseClock is the Lamport clock of the first op in Operations; it's
+	// carried alongside the batch so a receiver can sanity-check ordering
+	// without inspecting every op's ID.
+	BaseClock int `json:"baseClock,omitempty"`
+}
+
+// EncodeBinary gob-encodes msg for the binary WebSocket framing used once
+// both peers have negotiated ProtocolVersion >= 2. It's the binary
+// counterpart to plain JSON marshaling, which remains the wire format for
+// peers that haven't negotiated a version. It doesn't add its own length
+// prefix: gorilla/websocket already preserves message boundaries, so one
+// gob-encoded Message maps to exactly one WebSocket frame.
+func EncodeBinary(msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(msg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeBinary reverses EncodeBinary's gob encoding of a single WebSocket
+// frame.
+func DecodeBinary(data []byte) (Message, error) {
+	var msg Message
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&msg)
+	return msg, err
+}
@@ -0,0 +1,276 @@
+// Package crdt implements a replicated growable array (RGA) used to keep
+// pairpad's document convergent across concurrent edits.
+package crdt
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sort"
+)
+
+// SiteID identifies the local site within a collaborative session. It is
+// assigned by the server when a client connects.
+var SiteID int
+
+// clock is a monotonically increasing counter used to mint unique
+// Identifiers for locally-created elements.
+var clock int
+
+// Identifier uniquely and totally orders an Element, even when two sites
+// insert at the same logical position concurrently.
+type Identifier struct {
+	SiteID int
+	Clock  int
+}
+
+// Element is a single (possibly deleted) character in the document.
+type Element struct {
+	ID        Identifier
+	Value     string
+	Tombstone bool
+
+	// LeftOrigin is the Identifier of the element that was immediately to
+	// this one's left at the moment it was inserted (the zero Identifier
+	// if it was inserted at the very start of the document). It never
+	// changes afterwards, and it's what lets Merge interleave two
+	// divergent documents deterministically instead of just concatenating
+	// them.
+	LeftOrigin Identifier
+}
+
+// Document is an ordered list of Elements, tombstones included, forming
+// the shared text buffer.
+type Document struct {
+	Elements []Element
+}
+
+// nextID mints a fresh Identifier for the local site.
+func nextID() Identifier {
+	clock++
+	return Identifier{SiteID: SiteID, Clock: clock}
+}
+
+// visibleIndex walks doc.Elements and returns the slice index right after
+// the position'th visible (non-tombstoned) character: len(doc.Elements) if
+// position is past the end, or 0 if position is at or before the start of
+// the document.
+func visibleIndex(doc *Document, position int) int {
+	if position <= 0 {
+		return 0
+	}
+	count := 0
+	for i, el := range doc.Elements {
+		if el.Tombstone {
+			continue
+		}
+		count++
+		if count == position {
+			return i + 1
+		}
+	}
+	return len(doc.Elements)
+}
+
+// Insert inserts ch right after the position'th visible character (at the
+// very start of the document when position is 0), so that it becomes the
+// (position+1)'th visible character, and returns the document's new visible content along with the freshly
+// minted Identifier of the inserted element, so callers (e.g. the undo
+// stack) can address it directly later.
+func (doc *Document) Insert(position int, ch string) (string, Identifier, error) {
+	if position < 0 {
+		return Content(*doc), Identifier{}, errors.New("crdt: negative insert position")
+	}
+
+	idx := visibleIndex(doc, position)
+	el := Element{ID: nextID(), Value: ch}
+	if idx > 0 {
+		el.LeftOrigin = doc.Elements[idx-1].ID
+	}
+
+	doc.Elements = append(doc.Elements, Element{})
+	copy(doc.Elements[idx+1:], doc.Elements[idx:])
+	doc.Elements[idx] = el
+
+	return Content(*doc), el.ID, nil
+}
+
+// Delete tombstones the position'th visible character and returns the
+// document's new visible content along with the Identifier of the
+// tombstoned element, so callers can later restore that exact element.
+func (doc *Document) Delete(position int) (string, Identifier) {
+	idx := visibleIndex(doc, position) - 1
+	if idx < 0 || idx >= len(doc.Elements) {
+		return Content(*doc), Identifier{}
+	}
+	doc.Elements[idx].Tombstone = true
+	return Content(*doc), doc.Elements[idx].ID
+}
+
+// DeleteByID tombstones the element with the given ID directly, instead of
+// by visible position. Undo (of an insert) and redo (of a delete) use this
+// so replay stays correct even if positions have shifted since.
+func (doc *Document) DeleteByID(id Identifier) (string, bool) {
+	for i, el := range doc.Elements {
+		if el.ID == id {
+			doc.Elements[i].Tombstone = true
+			return Content(*doc), true
+		}
+	}
+	return Content(*doc), false
+}
+
+// UndeleteByID clears the tombstone on the element with the given ID,
+// restoring it to visibility in its original position. Undo (of a delete)
+// and redo (of an insert) use this.
+func (doc *Document) UndeleteByID(id Identifier) (string, bool) {
+	for i, el := range doc.Elements {
+		if el.ID == id {
+			doc.Elements[i].Tombstone = false
+			return Content(*doc), true
+		}
+	}
+	return Content(*doc), false
+}
+
+// ValueOf returns the character value of the element with the given ID,
+// even if it is currently tombstoned.
+func ValueOf(doc Document, id Identifier) (string, bool) {
+	for _, el := range doc.Elements {
+		if el.ID == id {
+			return el.Value, true
+		}
+	}
+	return "", false
+}
+
+// Content renders the visible (non-tombstoned) text of doc.
+func Content(doc Document) string {
+	var text []byte
+	for _, el := range doc.Elements {
+		if !el.Tombstone {
+			text = append(text, el.Value...)
+		}
+	}
+	return string(text)
+}
+
+// IdentifierAt returns the Identifier of the position'th visible character,
+// or the zero Identifier and false if position is out of range. It is used
+// to anchor things like remote cursors to stable CRDT positions instead of
+// raw, reflow-prone indices.
+func IdentifierAt(doc Document, position int) (Identifier, bool) {
+	count := 0
+	for _, el := range doc.Elements {
+		if el.Tombstone {
+			continue
+		}
+		count++
+		if count == position {
+			return el.ID, true
+		}
+	}
+	return Identifier{}, false
+}
+
+// PositionAt returns the visible-character position of the element with
+// the given Identifier, or -1 if no such element exists (e.g. it has since
+// been deleted).
+func PositionAt(doc Document, id Identifier) int {
+	count := 0
+	for _, el := range doc.Elements {
+		if el.Tombstone {
+			continue
+		}
+		count++
+		if el.ID == id {
+			return count
+		}
+	}
+	return -1
+}
+
+// Merge combines a and b into a single RGA-ordered Document. Elements are
+// first unioned by Identifier, OR-ing tombstone state for elements present
+// in both; the result is then linearized from each element's LeftOrigin,
+// with concurrent siblings (elements inserted at the same position without
+// having seen each other) ordered by Identifier, highest first. Since that
+// linearization depends only on the union of elements and never on which
+// of a or b it came from or the order they were passed in, Merge(a, b) and
+// Merge(b, a) always produce byte-identical output — which is what lets a
+// reconnecting client fold the server's authoritative copy into its own
+// offline edits without the two sides scrambling each other's text.
+func Merge(a, b Document) Document {
+	byID := make(map[Identifier]Element, len(a.Elements)+len(b.Elements))
+
+	add := func(el Element) {
+		existing, ok := byID[el.ID]
+		if !ok {
+			byID[el.ID] = el
+			return
+		}
+		existing.Tombstone = existing.Tombstone || el.Tombstone
+		byID[el.ID] = existing
+	}
+
+	for _, el := range a.Elements {
+		add(el)
+	}
+	for _, el := range b.Elements {
+		add(el)
+	}
+
+	children := make(map[Identifier][]Element, len(byID))
+	for _, el := range byID {
+		children[el.LeftOrigin] = append(children[el.LeftOrigin], el)
+	}
+	for origin := range children {
+		siblings := children[origin]
+		sort.Slice(siblings, func(i, j int) bool {
+			return higherPriority(siblings[i].ID, siblings[j].ID)
+		})
+	}
+
+	merged := Document{Elements: make([]Element, 0, len(byID))}
+	var linearize func(parent Identifier)
+	linearize = func(parent Identifier) {
+		for _, el := range children[parent] {
+			merged.Elements = append(merged.Elements, el)
+			linearize(el.ID)
+		}
+	}
+	linearize(Identifier{})
+
+	return merged
+}
+
+// higherPriority reports whether x should be linearized before y when both
+// are children of the same LeftOrigin. The tie-break is purely a function
+// of the Identifiers themselves, so it doesn't matter which site produced
+// x or y, or which order Merge saw them in.
+func higherPriority(x, y Identifier) bool {
+	if x.Clock != y.Clock {
+		return x.Clock > y.Clock
+	}
+	return x.SiteID > y.SiteID
+}
+
+// Save serializes doc to fileName as JSON.
+func Save(fileName string, doc *Document) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, data, 0644)
+}
+
+// Load reads a Document previously written by Save.
+func Load(fileName string) (Document, error) {
+	var doc Document
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return doc, err
+	}
+	err = json.Unmarshal(data, &doc)
+	return doc, err
+}
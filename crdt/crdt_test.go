@@ -0,0 +1,146 @@
+package crdt
+
+import "testing"
+
+// site is an independent site's SiteID and Lamport clock, used to generate
+// Identifiers the way two separate processes would — each with its own
+// counter, so two sites can legitimately mint the same Clock value.
+type site struct {
+	id    int
+	clock int
+}
+
+// do runs fn as s, swapping the package-level SiteID/clock in and restoring
+// them (along with s's own clock) afterwards, so concurrent sites in the
+// same test don't clobber each other's counters.
+func (s *site) do(t *testing.T, fn func()) {
+	t.Helper()
+	prevSite, prevClock := SiteID, clock
+	SiteID, clock = s.id, s.clock
+	fn()
+	s.clock = clock
+	SiteID, clock = prevSite, prevClock
+}
+
+func TestMergeIsCommutative(t *testing.T) {
+	site1 := &site{id: 1}
+	site2 := &site{id: 2}
+
+	var base Document
+	site1.do(t, func() {
+		base.Insert(0, "a")
+		base.Insert(1, "c")
+	})
+
+	docA := Document{Elements: append([]Element(nil), base.Elements...)}
+	docB := Document{Elements: append([]Element(nil), base.Elements...)}
+
+	// Two sites concurrently insert between "a" and "c" without seeing
+	// each other's edit.
+	site1.do(t, func() { docA.Insert(1, "b") })
+	site2.do(t, func() { docB.Insert(1, "X") })
+
+	ab := Merge(docA, docB)
+	ba := Merge(docB, docA)
+
+	if Content(ab) != Content(ba) {
+		t.Fatalf("Merge is not commutative: Merge(a,b) = %q, Merge(b,a) = %q", Content(ab), Content(ba))
+	}
+	if len(ab.Elements) != len(ba.Elements) {
+		t.Fatalf("Merge(a,b) and Merge(b,a) disagree on element count: %d vs %d", len(ab.Elements), len(ba.Elements))
+	}
+	for i := range ab.Elements {
+		if ab.Elements[i].ID != ba.Elements[i].ID {
+			t.Fatalf("element %d differs: Merge(a,b) has %v, Merge(b,a) has %v", i, ab.Elements[i].ID, ba.Elements[i].ID)
+		}
+	}
+}
+
+func TestMergeUnionsTombstones(t *testing.T) {
+	site1 := &site{id: 1}
+
+	var base Document
+	var delID Identifier
+	site1.do(t, func() {
+		base.Insert(0, "a")
+		base.Insert(1, "b")
+		_, delID = base.Delete(1)
+	})
+
+	docA := Document{Elements: append([]Element(nil), base.Elements...)}
+	docB := Document{Elements: append([]Element(nil), base.Elements...)}
+
+	// docB never learns about the deletion; docA already applied it.
+	for i := range docB.Elements {
+		if docB.Elements[i].ID == delID {
+			docB.Elements[i].Tombstone = false
+		}
+	}
+
+	merged := Merge(docA, docB)
+	for _, el := range merged.Elements {
+		if el.ID == delID && !el.Tombstone {
+			t.Fatalf("Merge dropped a tombstone known to only one side")
+		}
+	}
+}
+
+func TestDeleteByIDAndUndeleteByIDRoundTrip(t *testing.T) {
+	site1 := &site{id: 1}
+
+	var doc Document
+	var id Identifier
+	site1.do(t, func() {
+		doc.Insert(0, "a")
+		_, id, _ = doc.Insert(1, "b")
+		doc.Insert(2, "c")
+	})
+
+	if got := Content(doc); got != "abc" {
+		t.Fatalf("Content() = %q, want %q", got, "abc")
+	}
+
+	if text, ok := doc.DeleteByID(id); !ok || text != "ac" {
+		t.Fatalf("DeleteByID() = (%q, %v), want (%q, true)", text, ok, "ac")
+	}
+
+	if text, ok := doc.UndeleteByID(id); !ok || text != "abc" {
+		t.Fatalf("UndeleteByID() = (%q, %v), want (%q, true)", text, ok, "abc")
+	}
+
+	if value, ok := ValueOf(doc, id); !ok || value != "b" {
+		t.Fatalf("ValueOf() = (%q, %v), want (%q, true)", value, ok, "b")
+	}
+}
+
+func TestDeleteAtStartIsNoop(t *testing.T) {
+	site1 := &site{id: 1}
+
+	var doc Document
+	site1.do(t, func() {
+		doc.Insert(0, "a")
+		doc.Insert(1, "b")
+		doc.Insert(2, "c")
+	})
+
+	text, id := doc.Delete(0)
+	if text != "abc" {
+		t.Fatalf("Delete(0) on a non-empty document changed the text: got %q, want %q", text, "abc")
+	}
+	if id != (Identifier{}) {
+		t.Fatalf("Delete(0) reported a tombstoned element, want the zero Identifier: got %v", id)
+	}
+}
+
+func TestDeleteByIDUnknownIDIsNoop(t *testing.T) {
+	site1 := &site{id: 1}
+
+	var doc Document
+	site1.do(t, func() {
+		doc.Insert(0, "a")
+	})
+
+	if _, ok := doc.DeleteByID(Identifier{SiteID: 99, Clock: 99}); ok {
+		t.Fatalf("DeleteByID() with an unknown ID reported success")
+	}
+}
@@ -0,0 +1,332 @@
+// Package editor implements the gocui-backed views (editor, user sidebar,
+// status bar, and chat input) used by the pairpad client.
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// View names used when wiring up the layout in client/ui.go.
+const (
+	EditorView  = "editor"
+	SidebarView = "sidebar"
+	StatusView  = "status"
+	ChatView    = "chat"
+)
+
+// chatScrollback caps how many chat lines Editor keeps around for
+// rendering; older lines are dropped as new ones arrive.
+const chatScrollback = 100
+
+// Editor holds all of the client-side UI state: the visible text, cursor
+// position, and the channels used to coordinate redraws and status
+// messages with the rest of the program.
+type Editor struct {
+	Text   []rune
+	Cursor int
+
+	// CursorMu guards Cursor and SelectionStart, since they're read by the
+	// presence-broadcast timer goroutine while the UI goroutine moves the
+	// cursor or changes the selection. Use the locked SetText/SetX/
+	// MoveCursor/CursorPos/SelectionAnchor/SetSelectionAnchor methods
+	// instead of touching the fields directly.
+	CursorMu sync.Mutex
+
+	IsConnected bool
+	Users       []string
+
+	// SelectionStart marks the anchor of an in-progress shift+arrow
+	// selection; it is -1 when nothing is selected.
+	SelectionStart int
+
+	// RemoteCursors holds the last known caret position (and selection, if
+	// any) of every other site, keyed by site ID, for rendering presence.
+	RemoteCursors   map[int]RemoteCursor
+	RemoteCursorsMu sync.Mutex
+
+	StatusMu  sync.Mutex
+	StatusMsg string
+	ShowMsg   bool
+
+	StatusChan chan string
+	DrawChan   chan bool
+
+	// ChatMu guards ChatLines, InChatMode, and ChatInput below.
+	ChatMu     sync.Mutex
+	ChatLines  []string
+	InChatMode bool
+	ChatInput  []rune
+
+	// Gui is set once by client/main.go after the gocui.Gui is constructed,
+	// so Editor methods can trigger a redraw without the rest of the
+	// program reaching into gocui directly.
+	Gui *gocui.Gui
+}
+
+// RemoteCursor is a peer's last-reported cursor/selection, resolved to a
+// live position in the local buffer.
+type RemoteCursor struct {
+	Username string
+	Position int
+	Anchor   int // start of selection; equal to Position when nothing is selected
+	Color    string
+}
+
+// NewEditor returns an Editor with its channels initialized and ready to
+// use.
+func NewEditor() *Editor {
+	return &Editor{
+		SelectionStart: -1,
+		RemoteCursors:  make(map[int]RemoteCursor),
+		StatusChan:     make(chan string, 16),
+		DrawChan:       make(chan bool, 16),
+	}
+}
+
+// SetText replaces the editor's buffer with text.
+func (e *Editor) SetText(text string) {
+	e.CursorMu.Lock()
+	e.Text = []rune(text)
+	if e.Cursor > len(e.Text) {
+		e.Cursor = len(e.Text)
+	}
+	e.CursorMu.Unlock()
+}
+
+// SetX moves the cursor to the given absolute offset.
+func (e *Editor) SetX(x int) {
+	e.CursorMu.Lock()
+	e.Cursor = x
+	e.CursorMu.Unlock()
+}
+
+// MoveCursor shifts the cursor by dx, clamped to the bounds of the buffer.
+// dy is accepted for symmetry with arrow-key handling but is currently
+// unused since pairpad's buffer is a flat rune slice.
+func (e *Editor) MoveCursor(dx, dy int) {
+	e.CursorMu.Lock()
+	e.Cursor += dx
+	if e.Cursor < 0 {
+		e.Cursor = 0
+	}
+	if e.Cursor > len(e.Text) {
+		e.Cursor = len(e.Text)
+	}
+	e.CursorMu.Unlock()
+}
+
+// CursorPos returns the current cursor offset. Safe to call from any
+// goroutine, e.g. the presence-broadcast timer reading it concurrently
+// with the UI goroutine's edits.
+func (e *Editor) CursorPos() int {
+	e.CursorMu.Lock()
+	defer e.CursorMu.Unlock()
+	return e.Cursor
+}
+
+// SelectionAnchor returns the current selection anchor, or -1 if nothing
+// is selected. Safe to call from any goroutine; see CursorPos.
+func (e *Editor) SelectionAnchor() int {
+	e.CursorMu.Lock()
+	defer e.CursorMu.Unlock()
+	return e.SelectionStart
+}
+
+// SetSelectionAnchor sets the selection anchor directly, e.g. to mark the
+// start of a Shift+arrow selection or a Ctrl+A select-all's anchor at 0.
+func (e *Editor) SetSelectionAnchor(pos int) {
+	e.CursorMu.Lock()
+	e.SelectionStart = pos
+	e.CursorMu.Unlock()
+}
+
+// SetRemoteCursor records or updates the presence of a remote site.
+func (e *Editor) SetRemoteCursor(siteID int, rc RemoteCursor) {
+	e.RemoteCursorsMu.Lock()
+	e.RemoteCursors[siteID] = rc
+	e.RemoteCursorsMu.Unlock()
+}
+
+// AppendChatLine adds a formatted chat line to the scrollback, trimming
+// the oldest lines once chatScrollback is exceeded.
+func (e *Editor) AppendChatLine(line string) {
+	e.ChatMu.Lock()
+	e.ChatLines = append(e.ChatLines, line)
+	if over := len(e.ChatLines) - chatScrollback; over > 0 {
+		e.ChatLines = e.ChatLines[over:]
+	}
+	e.ChatMu.Unlock()
+}
+
+// SendDraw requests a redraw without blocking if one is already pending.
+func (e *Editor) SendDraw() {
+	select {
+	case e.DrawChan <- true:
+	default:
+	}
+}
+
+// Render repaints every view from the current Editor state. It's called
+// from client/ui.go's drawLoop whenever e.DrawChan fires.
+func (e *Editor) Render() error {
+	if e.Gui == nil {
+		return nil
+	}
+	e.Gui.Update(func(g *gocui.Gui) error {
+		if v, err := g.View(EditorView); err == nil {
+			e.drawEditor(v)
+		}
+		if v, err := g.View(SidebarView); err == nil {
+			e.drawSidebar(v)
+		}
+		if v, err := g.View(StatusView); err == nil {
+			e.drawStatus(v)
+		}
+		if v, err := g.View(ChatView); err == nil {
+			e.drawChat(v)
+		}
+		return nil
+	})
+	return nil
+}
+
+// drawEditor renders the buffer with remote carets/selections colorized
+// via ANSI escapes, which gocui passes straight through to the terminal.
+func (e *Editor) drawEditor(v *gocui.View) {
+	v.Clear()
+
+	e.CursorMu.Lock()
+	text := append([]rune(nil), e.Text...)
+	cursor := e.Cursor
+	e.CursorMu.Unlock()
+
+	covered := e.remoteCursorsByPosition()
+	var b strings.Builder
+	lastColor := ""
+	for pos, r := range text {
+		color := ""
+		if rc, ok := covered[pos]; ok {
+			color = rc.Color
+		}
+		if color != lastColor {
+			if lastColor != "" {
+				b.WriteString("\x1b[0m")
+			}
+			if color != "" {
+				b.WriteString(ansiReverse(color))
+			}
+			lastColor = color
+		}
+		b.WriteRune(r)
+	}
+	if lastColor != "" {
+		b.WriteString("\x1b[0m")
+	}
+
+	fmt.Fprint(v, b.String())
+	_ = v.SetCursor(cursorColRow(text, cursor))
+}
+
+// drawSidebar lists connected users, one per line.
+func (e *Editor) drawSidebar(v *gocui.View) {
+	v.Clear()
+	for _, u := range e.Users {
+		fmt.Fprintln(v, u)
+	}
+}
+
+// drawStatus renders the transient status-bar message, if any.
+func (e *Editor) drawStatus(v *gocui.View) {
+	v.Clear()
+	e.StatusMu.Lock()
+	defer e.StatusMu.Unlock()
+	if e.ShowMsg {
+		fmt.Fprint(v, e.StatusMsg)
+	}
+}
+
+// drawChat renders the scrollback lines that fit the view, followed by the
+// in-progress input line when InChatMode is active.
+func (e *Editor) drawChat(v *gocui.View) {
+	v.Clear()
+
+	e.ChatMu.Lock()
+	lines := e.ChatLines
+	inChatMode := e.InChatMode
+	input := string(e.ChatInput)
+	e.ChatMu.Unlock()
+
+	_, height := v.Size()
+	shown := lines
+	if len(shown) > height {
+		shown = shown[len(shown)-height:]
+	}
+	for _, line := range shown {
+		fmt.Fprintln(v, line)
+	}
+
+	if inChatMode {
+		fmt.Fprintf(v, "> %s", input)
+	}
+}
+
+// cursorColRow converts a flat rune-slice offset into the (col, row) gocui
+// expects, accounting for embedded newlines.
+func cursorColRow(text []rune, offset int) (int, int) {
+	col, row := 0, 0
+	for i := 0; i < offset && i < len(text); i++ {
+		if text[i] == '\n' {
+			col = 0
+			row++
+			continue
+		}
+		col++
+	}
+	return col, row
+}
+
+// remoteCursorsByPosition expands every RemoteCursor's (Anchor, Position)
+// range into a set of covered buffer offsets, so drawEditor can look up
+// each rune's highlight in O(1).
+func (e *Editor) remoteCursorsByPosition() map[int]RemoteCursor {
+	e.RemoteCursorsMu.Lock()
+	defer e.RemoteCursorsMu.Unlock()
+
+	covered := make(map[int]RemoteCursor, len(e.RemoteCursors))
+	for _, rc := range e.RemoteCursors {
+		lo, hi := rc.Anchor, rc.Position
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		if lo == hi {
+			covered[lo] = rc
+			continue
+		}
+		for p := lo; p < hi; p++ {
+			covered[p] = rc
+		}
+	}
+	return covered
+}
+
+// ansiReverse returns the ANSI escape sequence that sets a reverse-video
+// background of the given color name, used to render a remote caret or
+// selection without a per-cell styling API.
+func ansiReverse(color string) string {
+	codes := map[string]string{
+		"red":     "\x1b[7;31m",
+		"green":   "\x1b[7;32m",
+		"yellow":  "\x1b[7;33m",
+		"blue":    "\x1b[7;34m",
+		"magenta": "\x1b[7;35m",
+		"cyan":    "\x1b[7;36m",
+	}
+	if code, ok := codes[color]; ok {
+		return code
+	}
+	return "\x1b[7;36m"
+}